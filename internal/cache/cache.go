@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// HitKind distinguishes the three outcomes of a Cache.Get: a normal hit, a
+// tombstone recorded via Cache.SetNegative, or no entry at all.
+type HitKind int
+
+const (
+	Miss HitKind = iota
+	HitPositive
+	HitNegative
+)
+
+// Cache is the per-class cache backing a PageHandler's rendered output. A
+// Cache is scoped to a single host and class, and stores entries under the
+// "current generation" segment managed by the owning CacheManager.
+type Cache interface {
+	// Checksum returns the checksum of the generation currently being
+	// served.
+	Checksum() string
+	Class() string
+	Delete(ctx context.Context, key string) error
+	// Get looks up key and reports the kind of hit (or miss) and whether
+	// it came from the current generation (as opposed to the previous
+	// one, which is kept around briefly after a Cycle). For HitNegative,
+	// value is the reason passed to SetNegative.
+	Get(ctx context.Context, key string) (value string, kind HitKind, current bool, err error)
+	// GetOrLoad returns the cached value for key and the kind of hit it
+	// was. On a miss (kind == Miss) it calls loader to produce a value and
+	// stores the result via Set; concurrent misses for the same key share
+	// a single loader call. On HitNegative, value is the reason passed to
+	// SetNegative and loader is not called — callers must check kind
+	// rather than assume value is renderable content.
+	GetOrLoad(ctx context.Context, key string, loader func(context.Context) (string, error)) (value string, kind HitKind, err error)
+	Host() string
+	Set(ctx context.Context, key string, value string) error
+	// NegativeTTL is the configured TTL for entries stored via
+	// SetNegative, independent of TTL.
+	NegativeTTL() time.Duration
+	// SetNegative records a short-lived tombstone for key, so repeated
+	// requests for content that's missing or erroring upstream don't
+	// stampede the backend.
+	SetNegative(ctx context.Context, key string, reason string, ttl time.Duration) error
+	TTL() time.Duration
+	Uncycled() bool
+	// Revalidate asynchronously repopulates key by calling loader, sharing
+	// GetOrLoad's in-flight de-duplication so a burst of stale hits after a
+	// Cycle triggers at most one render per key.
+	Revalidate(ctx context.Context, key string, loader func(context.Context) (string, error))
+}
+
+// The HTTP layer sets X-Cache-Status to one of these values based on the
+// HitKind and generation returned from Cache.Get, so clients and operators
+// can see when a response was served stale during a generation swap.
+const (
+	HeaderCacheStatus = "X-Cache-Status"
+	CacheStatusHit    = "HIT"
+	CacheStatusStale  = "STALE"
+	CacheStatusMiss   = "MISS"
+)
+
+// CacheStatusFor maps a Get result to the X-Cache-Status value it should
+// produce at the HTTP layer.
+func CacheStatusFor(kind HitKind, current bool) string {
+	switch {
+	case kind == Miss:
+		return CacheStatusMiss
+	case current:
+		return CacheStatusHit
+	default:
+		return CacheStatusStale
+	}
+}
+
+// CacheManager owns the caches for a single host and coordinates generation
+// cycling across them.
+type CacheManager interface {
+	// Cycle advances the current generation to checksum. Caches created
+	// with Uncycled are left alone unless force is set, in which case
+	// every generation but checksum is dropped.
+	Cycle(checksum string, force bool) error
+	GetCache(class string, opts CacheOptions) Cache
+}
+
+// CacheOptions configures a Cache returned from CacheManager.GetCache.
+type CacheOptions struct {
+	// TTL overrides the manager's default entry TTL when set.
+	TTL *time.Duration
+	// Uncycled exempts the cache from generation cycling, e.g. for
+	// content that never changes between deploys.
+	Uncycled bool
+	// MaxEntries bounds the number of entries held per generation. Zero
+	// means unbounded. When set, entries are evicted using SIEVE once a
+	// generation reaches capacity.
+	MaxEntries int
+	// NegativeTTL is the default TTL callers should pass to SetNegative,
+	// configured independently of TTL. Nil falls back to TTL when the cache
+	// is created, and leaves the current value unchanged on later GetCache
+	// calls.
+	NegativeTTL *time.Duration
+	// StaleWhileRevalidate bounds how long a previous-generation hit may
+	// still be served after a Cycle. Zero means previous-generation hits
+	// are served for as long as that segment is kept around (today,
+	// until the next Cycle).
+	StaleWhileRevalidate time.Duration
+}
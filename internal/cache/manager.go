@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ManagerConfig selects and configures the CacheManager backend used for a
+// host. Callers only ever talk to the resulting CacheManager/Cache
+// interfaces, so switching Backend doesn't require touching call sites like
+// GetCache.
+type ManagerConfig struct {
+	// Backend is "memory" (the default) or "redis".
+	Backend string
+	// RedisClient is required when Backend is "redis".
+	RedisClient *redis.Client
+	// TTL is the default per-entry TTL for caches created by GetCache.
+	TTL time.Duration
+}
+
+const RedisBackend = "redis"
+
+// NewCacheManager builds the CacheManager backend selected by cfg.
+func NewCacheManager(host string, cfg ManagerConfig) CacheManager {
+	if cfg.Backend == RedisBackend {
+		return NewRedisCacheManager(host, cfg.RedisClient, cfg.TTL)
+	}
+	return NewInMemoryCacheManager(host, cfg.TTL)
+}
@@ -4,17 +4,24 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type InMemoryCache struct {
-	class           string
-	currentChecksum string
-	host            string
-	mu              sync.RWMutex
-	segments        map[string]map[string]memoryCacheEntry
-	ttl             time.Duration
-	uncycled        bool
-	updateChan      chan time.Duration
+	class                string
+	currentChecksum      string
+	cycledAt             time.Time
+	host                 string
+	maxEntries           int
+	mu                   sync.RWMutex
+	negativeTTL          time.Duration
+	segments             map[string]*sieveSegment
+	sf                   singleflight.Group
+	staleWhileRevalidate time.Duration
+	ttl                  time.Duration
+	uncycled             bool
+	updateChan           chan time.Duration
 }
 
 var _ Cache = (*InMemoryCache)(nil)
@@ -34,7 +41,7 @@ func (c *InMemoryCache) Delete(ctx context.Context, key string) error {
 	defer c.mu.Unlock()
 
 	for _, seg := range c.segments {
-		delete(seg, key)
+		seg.delete(key)
 	}
 	return nil
 }
@@ -43,6 +50,10 @@ func (c *InMemoryCache) Host() string {
 	return c.host
 }
 
+func (c *InMemoryCache) NegativeTTL() time.Duration {
+	return c.negativeTTL
+}
+
 func (c *InMemoryCache) TTL() time.Duration {
 	return c.ttl
 }
@@ -51,39 +62,100 @@ func (c *InMemoryCache) Uncycled() bool {
 	return c.uncycled
 }
 
-func (c *InMemoryCache) Get(ctx context.Context, key string) (string, bool, bool, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (c *InMemoryCache) Get(ctx context.Context, key string) (string, HitKind, bool, error) {
+	// A full Lock, not RLock: seg.get() marks the entry visited for SIEVE.
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	// 1. Try Current Generation
 	if seg, ok := c.segments[c.currentChecksum]; ok {
-		if entry, found := seg[key]; found {
+		if entry, found := seg.get(key); found {
 			// LAZY DELETION CHECK
 			if time.Now().After(entry.expiry) {
 				// Just pretend it's not found. The reaper will get it later.
-				return "", false, true, nil
+				return "", Miss, true, nil
+			}
+			if entry.negative {
+				return entry.reason, HitNegative, true, nil
 			}
-			return entry.value, true, true, nil // Found in current version
+			return entry.value, HitPositive, true, nil // Found in current version
 		}
 	}
 
-	// 2. Try Previous Generation (Searching for any other segment)
+	// 2. Try Previous Generation (Searching for any other segment), but
+	// only within the StaleWhileRevalidate window since the last Cycle.
 	// In a two-generation system, there will only be one other key.
+	if c.staleWhileRevalidate > 0 && time.Since(c.cycledAt) > c.staleWhileRevalidate {
+		return "", Miss, true, nil
+	}
 	for gen, seg := range c.segments {
 		if gen == c.currentChecksum {
 			continue
 		}
-		if entry, found := seg[key]; found {
+		if entry, found := seg.get(key); found {
 			// LAZY DELETION CHECK
 			if time.Now().After(entry.expiry) {
 				// Just pretend it's not found. The reaper will get it later.
-				return "", false, true, nil
+				return "", Miss, true, nil
 			}
-			return entry.value, true, false, nil // Found, but it's the old version
+			if entry.negative {
+				return entry.reason, HitNegative, false, nil
+			}
+			return entry.value, HitPositive, false, nil // Found, but it's the old version
 		}
 	}
 
-	return "", false, true, nil // Not found in either version
+	return "", Miss, true, nil // Not found in either version
+}
+
+// GetOrLoad returns the cached value for key and the kind of hit it was. If
+// multiple callers miss on the same key concurrently, only the first runs
+// loader; the rest block on its result. A HitNegative is returned as-is,
+// without calling loader: callers must check kind before treating value as
+// renderable content, since for HitNegative it's the SetNegative reason.
+func (c *InMemoryCache) GetOrLoad(ctx context.Context, key string, loader func(context.Context) (string, error)) (string, HitKind, error) {
+	if value, kind, _, err := c.Get(ctx, key); err != nil {
+		return "", Miss, err
+	} else if kind != Miss {
+		return value, kind, nil
+	}
+
+	value, err, _ := c.sf.Do(key, func() (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return "", err
+		}
+		if err := c.Set(ctx, key, v); err != nil {
+			return "", err
+		}
+		return v, nil
+	})
+	if err != nil {
+		return "", Miss, err
+	}
+	return value.(string), HitPositive, nil
+}
+
+// Revalidate kicks off loader in the background and stores its result,
+// sharing GetOrLoad's singleflight group so a burst of stale hits for the
+// same key after a Cycle only triggers one re-render.
+func (c *InMemoryCache) Revalidate(ctx context.Context, key string, loader func(context.Context) (string, error)) {
+	// The caller's ctx is typically canceled as soon as the stale response is
+	// served, which would abort loader before it can repopulate the current
+	// generation. Detach from cancellation, but keep any values it carries.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		c.sf.Do(key, func() (interface{}, error) {
+			v, err := loader(ctx)
+			if err != nil {
+				return "", err
+			}
+			if err := c.Set(ctx, key, v); err != nil {
+				return "", err
+			}
+			return v, nil
+		})
+	}()
 }
 
 // Set stores a rendered page in the cache.
@@ -91,14 +163,27 @@ func (c *InMemoryCache) Set(ctx context.Context, key string, value string) error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.segments[c.currentChecksum] == nil {
-		c.segments[c.currentChecksum] = make(map[string]memoryCacheEntry)
+	seg, ok := c.segments[c.currentChecksum]
+	if !ok {
+		seg = newSieveSegment(c.maxEntries)
+		c.segments[c.currentChecksum] = seg
 	}
+	seg.set(key, value, time.Now().Add(c.ttl))
+	return nil
+}
 
-	c.segments[c.currentChecksum][key] = memoryCacheEntry{
-		expiry: time.Now().Add(c.ttl),
-		value:  value,
+// SetNegative records a tombstone for key so repeated requests for content
+// that's missing or erroring upstream don't stampede the backend.
+func (c *InMemoryCache) SetNegative(ctx context.Context, key string, reason string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seg, ok := c.segments[c.currentChecksum]
+	if !ok {
+		seg = newSieveSegment(c.maxEntries)
+		c.segments[c.currentChecksum] = seg
 	}
+	seg.setNegative(key, reason, time.Now().Add(ttl))
 	return nil
 }
 
@@ -108,9 +193,9 @@ func (c *InMemoryCache) reap() {
 
 	now := time.Now()
 	for _, seg := range c.segments {
-		for key, entry := range seg {
+		for key, entry := range seg.entries {
 			if now.After(entry.expiry) {
-				delete(seg, key)
+				seg.delete(key)
 			}
 		}
 	}
@@ -146,6 +231,17 @@ type InMemoryCacheManager struct {
 
 var _ CacheManager = (*InMemoryCacheManager)(nil)
 
+// NewInMemoryCacheManager builds a CacheManager that keeps all cache
+// generations in process memory. ttl is the default per-entry TTL used by
+// caches created through GetCache that don't override it via CacheOptions.
+func NewInMemoryCacheManager(host string, ttl time.Duration) *InMemoryCacheManager {
+	return &InMemoryCacheManager{
+		caches: make(map[string]Cache),
+		host:   host,
+		ttl:    ttl,
+	}
+}
+
 func (m *InMemoryCacheManager) Cycle(checksum string, force bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -160,10 +256,11 @@ func (m *InMemoryCacheManager) Cycle(checksum string, force bool) error {
 			}
 			mCache.mu.Lock()
 			mCache.currentChecksum = checksum
+			mCache.cycledAt = time.Now()
 
-			// Ensure the new generation map exists
+			// Ensure the new generation segment exists
 			if mCache.segments[checksum] == nil {
-				mCache.segments[checksum] = make(map[string]memoryCacheEntry)
+				mCache.segments[checksum] = newSieveSegment(mCache.maxEntries)
 			}
 
 			// If forced, wipe all generations except the current one
@@ -201,6 +298,10 @@ func (m *InMemoryCacheManager) GetCache(class string, opts CacheOptions) Cache {
 			newTTL = opts.TTL
 			mCache.ttl = *newTTL
 		}
+		if opts.NegativeTTL != nil {
+			mCache.negativeTTL = *opts.NegativeTTL
+		}
+		mCache.staleWhileRevalidate = opts.StaleWhileRevalidate
 		mCache.mu.Unlock()
 		// Send to channel AFTER unlocking the mutex
 		if newTTL != nil {
@@ -220,20 +321,23 @@ func (m *InMemoryCacheManager) GetCache(class string, opts CacheOptions) Cache {
 	if opts.TTL != nil {
 		ttl = *opts.TTL
 	}
+	negativeTTL := ttl
+	if opts.NegativeTTL != nil {
+		negativeTTL = *opts.NegativeTTL
+	}
 	cache = &InMemoryCache{
-		class:           class,
-		currentChecksum: m.currentChecksum,
-		host:            m.host,
-		uncycled:        opts.Uncycled,
-		segments:        make(map[string]map[string]memoryCacheEntry),
-		ttl:             ttl,
+		class:                class,
+		currentChecksum:      m.currentChecksum,
+		cycledAt:             time.Now(),
+		host:                 m.host,
+		maxEntries:           opts.MaxEntries,
+		negativeTTL:          negativeTTL,
+		staleWhileRevalidate: opts.StaleWhileRevalidate,
+		uncycled:             opts.Uncycled,
+		segments:             make(map[string]*sieveSegment),
+		ttl:                  ttl,
 	}
 	go cache.(*InMemoryCache).startReaper(ttl)
 	m.caches[class] = cache
 	return cache
 }
-
-type memoryCacheEntry struct {
-	expiry time.Time
-	value  string
-}
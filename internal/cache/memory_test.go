@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryCacheGetStaleWhileRevalidateCutoff(t *testing.T) {
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+
+	newCacheWithPreviousGen := func(cycledAt time.Time, swr time.Duration) *InMemoryCache {
+		prev := newSieveSegment(0)
+		prev.set("key", "stale-value", exp)
+
+		cur := newSieveSegment(0)
+
+		return &InMemoryCache{
+			currentChecksum:      "current-gen",
+			cycledAt:             cycledAt,
+			staleWhileRevalidate: swr,
+			ttl:                  time.Hour,
+			segments: map[string]*sieveSegment{
+				"current-gen":  cur,
+				"previous-gen": prev,
+			},
+		}
+	}
+
+	t.Run("within window serves the previous generation", func(t *testing.T) {
+		c := newCacheWithPreviousGen(time.Now().Add(-30*time.Second), time.Minute)
+
+		value, kind, current, err := c.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Get returned error: %v", err)
+		}
+		if kind != HitPositive {
+			t.Fatalf("kind = %v, want HitPositive", kind)
+		}
+		if current {
+			t.Fatal("current = true, want false (served from previous generation)")
+		}
+		if value != "stale-value" {
+			t.Fatalf("value = %q, want %q", value, "stale-value")
+		}
+	})
+
+	t.Run("past window reports a miss instead of serving stale", func(t *testing.T) {
+		c := newCacheWithPreviousGen(time.Now().Add(-2*time.Minute), time.Minute)
+
+		_, kind, _, err := c.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Get returned error: %v", err)
+		}
+		if kind != Miss {
+			t.Fatalf("kind = %v, want Miss once past the StaleWhileRevalidate window", kind)
+		}
+	})
+
+	t.Run("zero StaleWhileRevalidate never cuts off the previous generation", func(t *testing.T) {
+		c := newCacheWithPreviousGen(time.Now().Add(-24*time.Hour), 0)
+
+		_, kind, current, err := c.Get(ctx, "key")
+		if err != nil {
+			t.Fatalf("Get returned error: %v", err)
+		}
+		if kind != HitPositive || current {
+			t.Fatalf("kind, current = %v, %v, want HitPositive, false", kind, current)
+		}
+	})
+}
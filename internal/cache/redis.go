@@ -0,0 +1,443 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// RedisCache is a Cache backed by a shared Redis instance, so that multiple
+// replicas of kdex-host serving the same PageHandler can share a rendered-
+// page cache instead of each keeping its own.
+//
+// Entries are namespaced by host, class and generation checksum, mirroring
+// the segment model InMemoryCache keeps in process memory. The "current"
+// and "previous" generation pointers are themselves stored in Redis so a
+// freshly started replica picks up the right generation immediately, and
+// are kept in sync across replicas by RedisCacheManager's pub/sub.
+type RedisCache struct {
+	class                string
+	client               redis.Cmdable
+	currentChecksum      string
+	cycledAt             time.Time
+	host                 string
+	mu                   sync.RWMutex
+	negativeTTL          time.Duration
+	sf                   singleflight.Group
+	staleWhileRevalidate time.Duration
+	ttl                  time.Duration
+	uncycled             bool
+}
+
+var _ Cache = (*RedisCache)(nil)
+
+func (c *RedisCache) Checksum() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.currentChecksum
+}
+
+func (c *RedisCache) Class() string {
+	return c.class
+}
+
+func (c *RedisCache) Host() string {
+	return c.host
+}
+
+func (c *RedisCache) NegativeTTL() time.Duration {
+	return c.negativeTTL
+}
+
+func (c *RedisCache) TTL() time.Duration {
+	return c.ttl
+}
+
+func (c *RedisCache) Uncycled() bool {
+	return c.uncycled
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	c.mu.RLock()
+	current := c.currentChecksum
+	c.mu.RUnlock()
+
+	keys := []string{c.entryKey(current, key)}
+	if prev, err := c.client.Get(ctx, c.previousGenerationKey()).Result(); err == nil && prev != "" && prev != current {
+		keys = append(keys, c.entryKey(prev, key))
+	} else if err != nil && err != redis.Nil {
+		return fmt.Errorf("cache: read previous generation: %w", err)
+	}
+
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, HitKind, bool, error) {
+	c.mu.RLock()
+	current := c.currentChecksum
+	cycledAt := c.cycledAt
+	staleWhileRevalidate := c.staleWhileRevalidate
+	c.mu.RUnlock()
+
+	// 1. Try Current Generation
+	value, kind, err := c.getGeneration(ctx, current, key)
+	if err != nil {
+		return "", Miss, true, err
+	}
+	if kind != Miss {
+		return value, kind, true, nil
+	}
+
+	// 2. Try Previous Generation, but only within the StaleWhileRevalidate
+	// window since the last Cycle.
+	if staleWhileRevalidate > 0 && time.Since(cycledAt) > staleWhileRevalidate {
+		return "", Miss, true, nil
+	}
+
+	prev, err := c.client.Get(ctx, c.previousGenerationKey()).Result()
+	if err != nil && err != redis.Nil {
+		return "", Miss, true, fmt.Errorf("cache: read previous generation: %w", err)
+	}
+	if prev != "" && prev != current {
+		value, kind, err = c.getGeneration(ctx, prev, key)
+		if err != nil {
+			return "", Miss, true, err
+		}
+		if kind != Miss {
+			return value, kind, false, nil
+		}
+	}
+
+	return "", Miss, true, nil
+}
+
+// getGeneration looks up key within a single generation, checking the
+// positive entry first and falling back to its tombstone.
+func (c *RedisCache) getGeneration(ctx context.Context, generation, key string) (string, HitKind, error) {
+	value, err := c.client.Get(ctx, c.entryKey(generation, key)).Result()
+	if err == nil {
+		return value, HitPositive, nil
+	}
+	if err != redis.Nil {
+		return "", Miss, fmt.Errorf("cache: get %s: %w", key, err)
+	}
+
+	reason, err := c.client.Get(ctx, c.negativeKey(generation, key)).Result()
+	if err == nil {
+		return reason, HitNegative, nil
+	}
+	if err != redis.Nil {
+		return "", Miss, fmt.Errorf("cache: get %s: %w", key, err)
+	}
+
+	return "", Miss, nil
+}
+
+// GetOrLoad returns the cached value for key and the kind of hit it was.
+// The singleflight dedup only covers callers within this process; replicas
+// that miss at the same time each still run loader once. A HitNegative is
+// returned as-is, without calling loader: callers must check kind before
+// treating value as renderable content, since for HitNegative it's the
+// SetNegative reason.
+func (c *RedisCache) GetOrLoad(ctx context.Context, key string, loader func(context.Context) (string, error)) (string, HitKind, error) {
+	if value, kind, _, err := c.Get(ctx, key); err != nil {
+		return "", Miss, err
+	} else if kind != Miss {
+		return value, kind, nil
+	}
+
+	value, err, _ := c.sf.Do(key, func() (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return "", err
+		}
+		if err := c.Set(ctx, key, v); err != nil {
+			return "", err
+		}
+		return v, nil
+	})
+	if err != nil {
+		return "", Miss, err
+	}
+	return value.(string), HitPositive, nil
+}
+
+// Revalidate kicks off loader in the background and stores its result,
+// sharing GetOrLoad's singleflight group so a burst of stale hits for the
+// same key after a Cycle only triggers one re-render within this process.
+func (c *RedisCache) Revalidate(ctx context.Context, key string, loader func(context.Context) (string, error)) {
+	// The caller's ctx is typically canceled as soon as the stale response is
+	// served, which would abort loader before it can repopulate the current
+	// generation. Detach from cancellation, but keep any values it carries.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		c.sf.Do(key, func() (interface{}, error) {
+			v, err := loader(ctx)
+			if err != nil {
+				return "", err
+			}
+			if err := c.Set(ctx, key, v); err != nil {
+				return "", err
+			}
+			return v, nil
+		})
+	}()
+}
+
+// Set stores a rendered page in the cache.
+func (c *RedisCache) Set(ctx context.Context, key string, value string) error {
+	c.mu.RLock()
+	current := c.currentChecksum
+	ttl := c.ttl
+	c.mu.RUnlock()
+
+	return c.client.Set(ctx, c.entryKey(current, key), value, ttl).Err()
+}
+
+// SetNegative records a tombstone for key so repeated requests for content
+// that's missing or erroring upstream don't stampede the backend.
+func (c *RedisCache) SetNegative(ctx context.Context, key string, reason string, ttl time.Duration) error {
+	c.mu.RLock()
+	current := c.currentChecksum
+	c.mu.RUnlock()
+
+	return c.client.Set(ctx, c.negativeKey(current, key), reason, ttl).Err()
+}
+
+// redisNamespace is the key prefix shared by every entry for a given
+// host/class, factored out so GetCache can look up the current generation
+// for a class before a RedisCache for it exists.
+func redisNamespace(host, class string) string {
+	return fmt.Sprintf("kdex-host:cache:%s:%s", host, class)
+}
+
+// namespace is the key prefix shared by every entry for this host/class.
+func (c *RedisCache) namespace() string {
+	return redisNamespace(c.host, c.class)
+}
+
+func (c *RedisCache) entryKey(generation, key string) string {
+	return fmt.Sprintf("%s:%s:%s", c.namespace(), generation, key)
+}
+
+func (c *RedisCache) currentGenerationKey() string {
+	return c.namespace() + ":current"
+}
+
+func (c *RedisCache) previousGenerationKey() string {
+	return c.namespace() + ":previous"
+}
+
+func (c *RedisCache) negativeKey(generation, key string) string {
+	return c.entryKey(generation, key) + ":neg"
+}
+
+// pruneSegments drops every entry whose generation is neither current nor
+// (unless force is set) previous, and records previous so Get knows how far
+// back it may still look. It's called once, by whichever replica's Cycle
+// triggered the swap; the resulting "previous" pointer is shared state in
+// Redis, so other replicas don't need to repeat the scan.
+func (c *RedisCache) pruneSegments(ctx context.Context, current, previous string, force bool) error {
+	prefix := c.namespace()
+	pattern := prefix + ":*"
+
+	keep := map[string]bool{current: true}
+	if !force && previous != "" && previous != current {
+		keep[previous] = true
+	}
+
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, 200).Result()
+		if err != nil {
+			return fmt.Errorf("cache: scan %s: %w", pattern, err)
+		}
+
+		for _, k := range keys {
+			if k == prefix+":previous" {
+				continue
+			}
+			rest := strings.TrimPrefix(k, prefix+":")
+			gen, _, ok := strings.Cut(rest, ":")
+			if !ok || keep[gen] {
+				continue
+			}
+			if err := c.client.Del(ctx, k).Err(); err != nil {
+				return fmt.Errorf("cache: del %s: %w", k, err)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if force || previous == "" || previous == current {
+		return c.client.Del(ctx, c.previousGenerationKey()).Err()
+	}
+	return c.client.Set(ctx, c.previousGenerationKey(), previous, 0).Err()
+}
+
+// RedisCacheManager coordinates Cache generations for a single host across
+// every replica sharing the same Redis instance: Cycle prunes stale
+// generations in Redis and publishes the new checksum so the other
+// replicas' local currentChecksum pointers swap in step.
+type RedisCacheManager struct {
+	caches          map[string]Cache
+	client          *redis.Client
+	currentChecksum string
+	host            string
+	mu              sync.RWMutex
+	ttl             time.Duration
+}
+
+var _ CacheManager = (*RedisCacheManager)(nil)
+
+// NewRedisCacheManager builds a CacheManager backed by client, sharing
+// generations across every replica subscribed to the same host's cycle
+// channel. ttl is the default per-entry TTL used by caches created through
+// GetCache that don't override it via CacheOptions.
+func NewRedisCacheManager(host string, client *redis.Client, ttl time.Duration) *RedisCacheManager {
+	m := &RedisCacheManager{
+		caches: make(map[string]Cache),
+		client: client,
+		host:   host,
+		ttl:    ttl,
+	}
+	go m.subscribeCycle()
+	return m
+}
+
+func (m *RedisCacheManager) cycleChannel() string {
+	return fmt.Sprintf("kdex-host:cache:%s:cycle", m.host)
+}
+
+// subscribeCycle keeps this replica's in-memory currentChecksum pointers in
+// step with whichever replica last called Cycle, without each replica
+// re-scanning Redis to prune segments itself.
+func (m *RedisCacheManager) subscribeCycle() {
+	ctx := context.Background()
+	sub := m.client.Subscribe(ctx, m.cycleChannel())
+	defer sub.Close()
+
+	for msg := range sub.Channel() {
+		m.mu.Lock()
+		m.currentChecksum = msg.Payload
+		for _, cache := range m.caches {
+			if rCache, ok := cache.(*RedisCache); ok {
+				// Cycle only moves an Uncycled cache's stored generation when
+				// forced, so follow that stored pointer rather than the payload.
+				if rCache.uncycled {
+					stored, err := m.client.Get(ctx, rCache.currentGenerationKey()).Result()
+					if err != nil || stored != msg.Payload {
+						continue
+					}
+				}
+				rCache.mu.Lock()
+				rCache.currentChecksum = msg.Payload
+				rCache.cycledAt = time.Now()
+				rCache.mu.Unlock()
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *RedisCacheManager) Cycle(checksum string, force bool) error {
+	ctx := context.Background()
+
+	m.mu.Lock()
+	oldChecksum := m.currentChecksum
+	m.currentChecksum = checksum
+	caches := make([]Cache, 0, len(m.caches))
+	for _, cache := range m.caches {
+		caches = append(caches, cache)
+	}
+	m.mu.Unlock()
+
+	for _, cache := range caches {
+		rCache, ok := cache.(*RedisCache)
+		if !ok {
+			continue
+		}
+		if rCache.uncycled && !force {
+			continue
+		}
+
+		rCache.mu.Lock()
+		rCache.currentChecksum = checksum
+		rCache.cycledAt = time.Now()
+		rCache.mu.Unlock()
+
+		if err := rCache.client.Set(ctx, rCache.currentGenerationKey(), checksum, 0).Err(); err != nil {
+			return fmt.Errorf("cache: set current generation: %w", err)
+		}
+
+		if err := rCache.pruneSegments(ctx, checksum, oldChecksum, force); err != nil {
+			return err
+		}
+	}
+
+	return m.client.Publish(ctx, m.cycleChannel(), checksum).Err()
+}
+
+func (m *RedisCacheManager) GetCache(class string, opts CacheOptions) Cache {
+	m.mu.RLock()
+	cache, ok := m.caches[class]
+	m.mu.RUnlock()
+
+	if ok {
+		rCache := cache.(*RedisCache)
+		rCache.mu.Lock()
+		rCache.uncycled = opts.Uncycled
+		if opts.TTL != nil {
+			rCache.ttl = *opts.TTL
+		}
+		if opts.NegativeTTL != nil {
+			rCache.negativeTTL = *opts.NegativeTTL
+		}
+		rCache.staleWhileRevalidate = opts.StaleWhileRevalidate
+		rCache.mu.Unlock()
+		return cache
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ttl := m.ttl
+	if opts.TTL != nil {
+		ttl = *opts.TTL
+	}
+	negativeTTL := ttl
+	if opts.NegativeTTL != nil {
+		negativeTTL = *opts.NegativeTTL
+	}
+
+	// A freshly started replica has no in-process currentChecksum yet, so
+	// bootstrap from the generation the last Cycle persisted to Redis
+	// rather than defaulting to "" and serving from a namespace no other
+	// replica uses.
+	currentChecksum := m.currentChecksum
+	if stored, err := m.client.Get(context.Background(), redisNamespace(m.host, class)+":current").Result(); err == nil && stored != "" {
+		currentChecksum = stored
+	}
+
+	cache = &RedisCache{
+		class:                class,
+		client:               m.client,
+		currentChecksum:      currentChecksum,
+		cycledAt:             time.Now(),
+		host:                 m.host,
+		negativeTTL:          negativeTTL,
+		staleWhileRevalidate: opts.StaleWhileRevalidate,
+		ttl:                  ttl,
+		uncycled:             opts.Uncycled,
+	}
+	m.caches[class] = cache
+	return cache
+}
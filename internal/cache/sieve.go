@@ -0,0 +1,165 @@
+package cache
+
+import "time"
+
+// memoryCacheEntry is one key/value pair within a sieveSegment. prev/next
+// link it into that segment's eviction order; key is kept alongside the
+// value so a deleted or evicted node can be removed from the map without a
+// second lookup.
+type memoryCacheEntry struct {
+	key      string
+	value    string
+	expiry   time.Time
+	visited  bool
+	negative bool
+	reason   string
+	prev     *memoryCacheEntry
+	next     *memoryCacheEntry
+}
+
+// sieveSegment is one cache generation's key/value store. When maxEntries
+// is 0 it behaves like a plain unbounded map. Otherwise, once it reaches
+// capacity, new keys evict an existing entry using SIEVE: a hand walks the
+// entries from head (oldest) toward tail (newest), clearing visited bits as
+// it passes and evicting the first entry it finds still unvisited. The hand
+// is left just past the entry it evicted rather than resetting to head on
+// the next call, which is what distinguishes SIEVE from CLOCK.
+type sieveSegment struct {
+	entries    map[string]*memoryCacheEntry
+	head, tail *memoryCacheEntry
+	hand       *memoryCacheEntry
+	maxEntries int
+}
+
+func newSieveSegment(maxEntries int) *sieveSegment {
+	return &sieveSegment{
+		entries:    make(map[string]*memoryCacheEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+func (s *sieveSegment) get(key string) (memoryCacheEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return memoryCacheEntry{}, false
+	}
+	e.visited = true
+	return *e, true
+}
+
+func (s *sieveSegment) set(key, value string, expiry time.Time) {
+	s.put(key, memoryCacheEntry{value: value, expiry: expiry})
+}
+
+func (s *sieveSegment) setNegative(key, reason string, expiry time.Time) {
+	s.put(key, memoryCacheEntry{negative: true, reason: reason, expiry: expiry})
+}
+
+// put inserts or overwrites key with data's value/negative/reason/expiry,
+// evicting via SIEVE if the segment is at capacity.
+func (s *sieveSegment) put(key string, data memoryCacheEntry) {
+	if e, ok := s.entries[key]; ok {
+		e.value, e.negative, e.reason, e.expiry = data.value, data.negative, data.reason, data.expiry
+		e.visited = true
+		return
+	}
+
+	if s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evict(key, data)
+		return
+	}
+
+	e := &memoryCacheEntry{
+		key:      key,
+		value:    data.value,
+		negative: data.negative,
+		reason:   data.reason,
+		expiry:   data.expiry,
+	}
+	s.pushTail(e)
+	s.entries[key] = e
+}
+
+func (s *sieveSegment) delete(key string) {
+	e, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	delete(s.entries, key)
+	s.unlink(e)
+}
+
+// evict advances the hand from its last position toward the tail, clearing
+// visited bits until it finds an entry to reclaim, then inserts the new
+// entry before the evicted position and leaves the hand pointing at the
+// node that follows it, so the hand doesn't need to move again on the next
+// call.
+func (s *sieveSegment) evict(key string, data memoryCacheEntry) {
+	node := s.hand
+	if node == nil {
+		node = s.head
+	}
+
+	for node != nil && node.visited {
+		node.visited = false
+		node = node.next
+		if node == nil {
+			node = s.head
+		}
+	}
+
+	e := &memoryCacheEntry{key: key, value: data.value, negative: data.negative, reason: data.reason, expiry: data.expiry}
+
+	if node == nil {
+		// Nothing to evict, e.g. maxEntries <= 0 slipped through.
+		s.pushTail(e)
+		s.entries[key] = e
+		return
+	}
+
+	s.hand = node.next
+	delete(s.entries, node.key)
+
+	// Splice e into node's exact list position, then drop node.
+	e.prev, e.next = node.prev, node.next
+	if node.prev != nil {
+		node.prev.next = e
+	} else {
+		s.head = e
+	}
+	if node.next != nil {
+		node.next.prev = e
+	} else {
+		s.tail = e
+	}
+	node.prev, node.next = nil, nil
+
+	s.entries[key] = e
+}
+
+func (s *sieveSegment) pushTail(e *memoryCacheEntry) {
+	if s.tail == nil {
+		s.head, s.tail = e, e
+		return
+	}
+	e.prev = s.tail
+	s.tail.next = e
+	s.tail = e
+}
+
+func (s *sieveSegment) unlink(e *memoryCacheEntry) {
+	if s.hand == e {
+		s.hand = e.next
+	}
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		s.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		s.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func (s *sieveSegment) order() []string {
+	var keys []string
+	for e := s.head; e != nil; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func TestSieveSegmentEvictsUnvisited(t *testing.T) {
+	s := newSieveSegment(3)
+	exp := time.Now().Add(time.Hour)
+
+	s.set("a", "1", exp)
+	s.set("b", "2", exp)
+	s.set("c", "3", exp)
+
+	// Touch "a" so it's visited; "b" and "c" are not.
+	s.get("a")
+
+	// Segment is at capacity: "d" must evict the first unvisited entry
+	// walking from head, which is "b".
+	s.set("d", "4", exp)
+
+	if _, ok := s.entries["b"]; ok {
+		t.Fatalf("expected %q to be evicted, still present", "b")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := s.entries[key]; !ok {
+			t.Fatalf("expected %q to be present, missing", key)
+		}
+	}
+}
+
+func TestSieveSegmentHandPersistsAcrossEvictions(t *testing.T) {
+	s := newSieveSegment(2)
+	exp := time.Now().Add(time.Hour)
+
+	s.set("a", "1", exp)
+	s.set("b", "2", exp)
+
+	// Neither "a" nor "b" is visited, so "c" evicts "a" (the head) and
+	// leaves the hand at "b" rather than resetting to the new head.
+	s.set("c", "3", exp)
+	if _, ok := s.entries["a"]; ok {
+		t.Fatalf("expected %q to be evicted", "a")
+	}
+	if s.hand == nil || s.hand.key != "b" {
+		t.Fatalf("expected hand to point at %q, got %v", "b", s.hand)
+	}
+
+	// Next eviction continues from "b" instead of restarting at head,
+	// which is what distinguishes SIEVE from CLOCK.
+	s.set("d", "4", exp)
+	if _, ok := s.entries["b"]; ok {
+		t.Fatalf("expected %q to be evicted next, following the hand", "b")
+	}
+	for _, key := range []string{"c", "d"} {
+		if _, ok := s.entries[key]; !ok {
+			t.Fatalf("expected %q to be present, missing", key)
+		}
+	}
+}
+
+func TestSieveSegmentEvictionPreservesListOrder(t *testing.T) {
+	s := newSieveSegment(3)
+	exp := time.Now().Add(time.Hour)
+
+	s.set("a", "1", exp)
+	s.set("b", "2", exp)
+	s.set("c", "3", exp)
+	s.get("b") // visited, so "a" is evicted first
+
+	s.set("d", "4", exp)
+
+	got := s.order()
+	want := []string{"d", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package page
 import (
 	"crypto/sha256"
 	"fmt"
+	"hash"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,11 +22,93 @@ fetch('/-/navigation/%s/[[ .Language ]]%s')
   });
 </script>
 </nav>`
-	rawHTMLTemplate = `<div data-content-id="%s">%s</div>`
+	rawHTMLTemplate   = `<div data-content-id="%s">%s</div>`
+	tombstoneTemplate = `<div data-content-id="%s" data-tombstone="%s"></div>`
 )
 
+// TombstoneHTML renders the placeholder used in place of a slot's custom
+// element when the cache holds a negative (tombstone) entry for it, e.g.
+// because upstream content resolution returned "not found" or a transient
+// error. Callers should skip PackedContent.ToHTML entirely for such slots.
+func TombstoneHTML(slot, reason string) string {
+	return fmt.Sprintf(tombstoneTemplate, slot, reason)
+}
+
 func (p *PageHandler) CacheKey(l language.Tag) string {
-	return fmt.Sprintf("%s:%s:%s", p.Name, p.Checksum(), l.String())
+	checksum := p.ContentChecksum()
+	if checksum == "" {
+		checksum = p.Checksum()
+	}
+	return fmt.Sprintf("%s:%s:%s", p.Name, checksum, l.String())
+}
+
+// ContentChecksum hashes the fully resolved render inputs rather than the
+// raw Status: each slot's packed content, the navigation keys, and the
+// page's own metadata. Unlike Checksum, this is content-addressable, so a
+// cache entry keyed by it stays valid across unrelated Status changes and is
+// invalidated as soon as any real render input changes. It returns "" when
+// there is nothing to render, in which case CacheKey falls back to
+// Checksum.
+func (p *PageHandler) ContentChecksum() string {
+	if p.Page == nil && len(p.Content) == 0 && len(p.Navigations) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+
+	slots := make([]string, 0, len(p.Content))
+	for slot := range p.Content {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+
+	writeChecksumField(h, strconv.Itoa(len(slots)))
+	for _, slot := range slots {
+		content := p.Content[slot]
+		writeChecksumField(h, slot)
+		writeChecksumField(h, content.CustomElementName)
+		writeChecksumField(h, content.AppName)
+		writeChecksumField(h, content.AppGeneration)
+		writeChecksumField(h, content.Content)
+
+		attrKeys := make([]string, 0, len(content.Attributes))
+		for k := range content.Attributes {
+			attrKeys = append(attrKeys, k)
+		}
+		sort.Strings(attrKeys)
+		writeChecksumField(h, strconv.Itoa(len(attrKeys)))
+		for _, k := range attrKeys {
+			writeChecksumField(h, k)
+			writeChecksumField(h, content.Attributes[k])
+		}
+	}
+
+	navKeys := make([]string, 0, len(p.Navigations))
+	for navKey := range p.Navigations {
+		navKeys = append(navKeys, navKey)
+	}
+	sort.Strings(navKeys)
+	writeChecksumField(h, strconv.Itoa(len(navKeys)))
+	for _, navKey := range navKeys {
+		writeChecksumField(h, navKey)
+	}
+
+	writeChecksumField(h, p.PatternPath())
+	writeChecksumField(h, p.Label())
+	writeChecksumField(h, p.BasePath())
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// writeChecksumField writes s to h preceded by its length, so that the
+// written fields for a given call sequence can't be reconstructed any other
+// way. ContentChecksum also writes the count of each variable-length list
+// (slots, attributes, navigation keys) before its items, so a varying number
+// of fields (e.g. a slot with more attributes) can't shift where one list
+// ends and the next begins.
+func writeChecksumField(h hash.Hash, s string) {
+	fmt.Fprintf(h, "%d:", len(s))
+	h.Write([]byte(s))
 }
 
 func (p *PageHandler) Checksum() string {